@@ -0,0 +1,41 @@
+package pageloadstats
+
+import "fmt"
+
+// BackendType selects which browser engine a worker uses to gather
+// page load measurements.
+type BackendType int
+
+const (
+	// BackendPhantomJS drives a PhantomJS process per worker.
+	//
+	// Deprecated: PhantomJS is unmaintained upstream; prefer
+	// BackendChromedp for new code.
+	BackendPhantomJS BackendType = iota
+	// BackendChromedp drives headless Chromium over the Chrome
+	// DevTools Protocol.
+	BackendChromedp
+)
+
+// backend is the engine-specific half of a worker: it knows how to
+// load a page and collect measurements for it, but nothing about how
+// workers are pooled or acquired.
+type backend interface {
+	// measure loads rawurl and returns the resulting measurements.
+	// If thumbnailFile is non-empty, a screenshot is written there.
+	measure(rawurl string, thumbnailFile string) (*PageMeasurements, error)
+	// close releases any resources (process, browser context) held by
+	// the backend.
+	close()
+}
+
+func newBackend(backendType BackendType) (backend, error) {
+	switch backendType {
+	case BackendPhantomJS:
+		return newPhantomBackend()
+	case BackendChromedp:
+		return newChromedpBackend()
+	default:
+		return nil, fmt.Errorf("pageloadstats: unknown backend type %d", backendType)
+	}
+}
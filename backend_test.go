@@ -0,0 +1,41 @@
+package pageloadstats
+
+import "sync"
+
+// fakeBackend is a backend that never touches a real browser, so the
+// pool/cancellation/batch logic can be tested without phantomjs or
+// Chromium installed.
+type fakeBackend struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *fakeBackend) measure(rawurl string, thumbnailFile string) (*PageMeasurements, error) {
+	return &PageMeasurements{Responses: map[int32]RequestMeasurements{}}, nil
+}
+
+func (b *fakeBackend) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+func (b *fakeBackend) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+func newTestPool(size int) *workersPool {
+	p := &workersPool{
+		idle: make(chan backend, size),
+		size: size,
+		newBackend: func() (backend, error) {
+			return &fakeBackend{}, nil
+		},
+	}
+	for i := 0; i < size; i++ {
+		p.idle <- &fakeBackend{}
+	}
+	return p
+}
@@ -0,0 +1,56 @@
+package pageloadstats
+
+import "context"
+
+// BatchResult carries the outcome of measuring a single URL as part
+// of a GetMeasurementsBatch call.
+type BatchResult struct {
+	URL          string
+	Measurements *PageMeasurements
+	Err          error
+}
+
+// GetMeasurementsBatch measures urls concurrently, using at most one
+// worker per URL at a time, and streams results back as they
+// complete. The returned channel is closed once every URL has been
+// measured or ctx is done.
+func (loadTimer *workersPool) GetMeasurementsBatch(ctx context.Context, urls []string, thumbnailsDir string) (<-chan BatchResult, error) {
+	input := make(chan string)
+	output := make(chan BatchResult, len(urls))
+
+	workerCount := loadTimer.size
+	if len(urls) < workerCount {
+		workerCount = len(urls)
+	}
+
+	done := make(chan struct{})
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			for rawurl := range input {
+				measurements, err := loadTimer.GetMeasurementsCtx(ctx, rawurl, thumbnailsDir)
+				output <- BatchResult{URL: rawurl, Measurements: measurements, Err: err}
+			}
+			done <- struct{}{}
+		}()
+	}
+
+	go func() {
+		defer close(input)
+		for _, rawurl := range urls {
+			select {
+			case input <- rawurl:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for i := 0; i < workerCount; i++ {
+			<-done
+		}
+		close(output)
+	}()
+
+	return output, nil
+}
@@ -0,0 +1,49 @@
+package pageloadstats
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetMeasurementsBatch(t *testing.T) {
+	p := newTestPool(2)
+
+	urls := []string{
+		"http://example.com/1",
+		"http://example.com/2",
+		"http://example.com/3",
+	}
+
+	results, err := p.GetMeasurementsBatch(context.Background(), urls, "")
+	if err != nil {
+		t.Fatalf("GetMeasurementsBatch: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	timeout := time.After(time.Second)
+	for len(seen) < len(urls) {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				t.Fatalf("results closed early, got %d/%d", len(seen), len(urls))
+			}
+			if res.Err != nil {
+				t.Fatalf("unexpected error for %s: %v", res.URL, res.Err)
+			}
+			seen[res.URL] = true
+		case <-timeout:
+			t.Fatalf("timed out waiting for batch results, got %d/%d", len(seen), len(urls))
+		}
+	}
+
+	if _, ok := <-results; ok {
+		t.Fatal("expected results channel to be closed once every URL is measured")
+	}
+
+	for _, url := range urls {
+		if !seen[url] {
+			t.Fatalf("missing result for %s", url)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package pageloadstats
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// chromedpAlloc is the single headless Chromium process shared by
+// every chromedpBackend. Workers are tabs (browser contexts) inside
+// it rather than separate processes, which is what keeps the
+// Chromium backend's memory footprint flat as the pool grows.
+//
+// It is recreated whenever the reference count goes from 0 back to 1
+// rather than started once via sync.Once: the pool's release() closes
+// a backend (dropping a reference) whenever a context is cancelled or
+// times out, and with a pool of size 1 that can drop the refcount to
+// 0 and cancel chromedpAllocCtx. A sync.Once would then keep handing
+// out that dead context forever, permanently breaking the pool after
+// exactly one timeout.
+var (
+	chromedpAllocMu     sync.Mutex
+	chromedpAllocCtx    context.Context
+	chromedpAllocCancel context.CancelFunc
+	chromedpAllocRefs   int
+)
+
+// chromedpAllocator returns the shared allocator context, (re)starting
+// the underlying Chromium process if there are currently no other
+// references to it, and registers the caller as a reference holder.
+// Every call must be paired with a releaseChromedpAllocator call once
+// the caller is done with its tab.
+func chromedpAllocator() context.Context {
+	chromedpAllocMu.Lock()
+	defer chromedpAllocMu.Unlock()
+
+	if chromedpAllocRefs == 0 {
+		chromedpAllocCtx, chromedpAllocCancel = chromedp.NewExecAllocator(context.Background(), chromedp.DefaultExecAllocatorOptions[:]...)
+	}
+	chromedpAllocRefs++
+
+	return chromedpAllocCtx
+}
+
+// releaseChromedpAllocator drops a reference to the shared allocator,
+// killing the underlying Chromium process once the last backend using
+// it has closed.
+func releaseChromedpAllocator() {
+	chromedpAllocMu.Lock()
+	defer chromedpAllocMu.Unlock()
+
+	chromedpAllocRefs--
+	if chromedpAllocRefs <= 0 && chromedpAllocCancel != nil {
+		chromedpAllocCancel()
+	}
+}
+
+// chromedpBackend implements backend by driving a tab of the shared
+// Chromium process over the Chrome DevTools Protocol, using the
+// Network domain to gather per-request timing. The page load time is
+// the wall-clock time of the whole measure run; the Performance domain
+// is not used.
+type chromedpBackend struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	requests map[network.RequestID]*RequestMeasurements
+}
+
+func newChromedpBackend() (backend, error) {
+	ctx, cancel := chromedp.NewContext(chromedpAllocator())
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		releaseChromedpAllocator()
+		return nil, err
+	}
+
+	b := &chromedpBackend{ctx: ctx, cancel: cancel, requests: make(map[network.RequestID]*RequestMeasurements)}
+
+	// Registered once for the lifetime of the tab: chromedp has no way
+	// to unregister a target listener, so re-registering on every
+	// measure() call would leave one extra stale handler per page load
+	// running forever. requests is reset at the start of each measure
+	// call instead.
+	chromedp.ListenTarget(ctx, b.onEvent)
+
+	return b, nil
+}
+
+func (b *chromedpBackend) onEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		b.mu.Lock()
+		b.requests[e.RequestID] = &RequestMeasurements{
+			StartTime:      e.Timestamp.Time(),
+			URL:            e.Request.URL,
+			Method:         e.Request.Method,
+			RequestHeaders: headersToStringMap(e.Request.Headers),
+		}
+		b.mu.Unlock()
+	case *network.EventResponseReceived:
+		b.mu.Lock()
+		if req, ok := b.requests[e.RequestID]; ok {
+			req.Status = int32(e.Response.Status)
+			req.ContentType = e.Response.MimeType
+			req.ResponseHeaders = headersToStringMap(e.Response.Headers)
+			req.Timings = resourceTimingToRequestTimings(e.Response.Timing)
+		}
+		b.mu.Unlock()
+	case *network.EventLoadingFinished:
+		b.mu.Lock()
+		if req, ok := b.requests[e.RequestID]; ok {
+			req.EndTime = e.Timestamp.Time()
+			req.BodySize = int64(e.EncodedDataLength)
+			req.RunningTime = req.EndTime.Sub(req.StartTime).Milliseconds()
+			req.Timings.Receive = req.RunningTime - req.Timings.Wait
+		}
+		b.mu.Unlock()
+	}
+}
+
+// resourceTimingToRequestTimings converts a CDP ResourceTiming into
+// RequestTimings. CDP uses -1 as a "phase not applicable" sentinel
+// (e.g. every phase up to send is -1 for requests reusing a keep-alive
+// connection, which is the common case), so phases with a -1 operand
+// are left at their zero value rather than producing garbage deltas.
+func resourceTimingToRequestTimings(t *network.ResourceTiming) RequestTimings {
+	var timings RequestTimings
+	if t == nil {
+		return timings
+	}
+
+	if t.DNSStart >= 0 {
+		timings.Blocked = int64(t.DNSStart)
+	}
+	if t.DNSStart >= 0 && t.DNSEnd >= 0 {
+		timings.DNS = int64(t.DNSEnd - t.DNSStart)
+	}
+	if t.ConnectStart >= 0 && t.ConnectEnd >= 0 {
+		timings.Connect = int64(t.ConnectEnd - t.ConnectStart)
+	}
+	if t.SendStart >= 0 && t.SendEnd >= 0 {
+		timings.Send = int64(t.SendEnd - t.SendStart)
+	}
+	if t.SendEnd >= 0 && t.ReceiveHeadersEnd >= 0 {
+		timings.Wait = int64(t.ReceiveHeadersEnd - t.SendEnd)
+	}
+
+	return timings
+}
+
+func (b *chromedpBackend) measure(rawurl string, thumbnailFile string) (*PageMeasurements, error) {
+	b.mu.Lock()
+	b.requests = make(map[network.RequestID]*RequestMeasurements)
+	b.mu.Unlock()
+
+	var screenshot []byte
+	actions := []chromedp.Action{
+		network.Enable(),
+		chromedp.Navigate(rawurl),
+		chromedp.WaitReady("body"),
+	}
+	if thumbnailFile != "" {
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			screenshot, err = page.CaptureScreenshot().Do(ctx)
+			return err
+		}))
+	}
+
+	start := time.Now()
+	if err := chromedp.Run(b.ctx, actions...); err != nil {
+		return nil, err
+	}
+	loadTime := time.Since(start).Milliseconds()
+
+	if len(screenshot) > 0 {
+		if err := ioutil.WriteFile(thumbnailFile, screenshot, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	b.mu.Lock()
+	performance := &PageMeasurements{
+		LoadTime:  loadTime,
+		Responses: make(map[int32]RequestMeasurements, len(b.requests)),
+	}
+	var id int32
+	for _, req := range b.requests {
+		performance.Responses[id] = *req
+		id++
+	}
+	b.mu.Unlock()
+
+	return performance, nil
+}
+
+func (b *chromedpBackend) close() {
+	b.cancel()
+	releaseChromedpAllocator()
+}
+
+func headersToStringMap(headers network.Headers) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if s, ok := value.(string); ok {
+			result[name] = s
+		}
+	}
+	return result
+}
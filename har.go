@@ -0,0 +1,178 @@
+package pageloadstats
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// harCreatorName/harCreatorVersion identify this library as the tool
+// that produced the archive, per the HAR 1.2 spec.
+const (
+	harVersion        = "1.2"
+	harCreatorName    = "pageloadstats"
+	harCreatorVersion = "1.0"
+)
+
+// harLog is the root object of a HAR 1.2 document.
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Pages   []harPage  `json:"pages"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     harPageTimings `json:"pageTimings"`
+}
+
+type harPageTimings struct {
+	OnContentLoad int64 `json:"onContentLoad"`
+	OnLoad        int64 `json:"onLoad"`
+}
+
+type harEntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	QueryString []harHeader `json:"queryString"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int32       `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked int64 `json:"blocked"`
+	DNS     int64 `json:"dns"`
+	Connect int64 `json:"connect"`
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+}
+
+// ToHAR serializes the measurements as an HTTP Archive (HAR) 1.2
+// document, so results can be fed into standard HAR viewers and diff
+// tools without any post-processing.
+func (pm *PageMeasurements) ToHAR() ([]byte, error) {
+	pageID := "page_1"
+	startedDateTime := pm.StartedDateTime.UTC().Format(time.RFC3339Nano)
+
+	doc := harDocument{
+		Log: harLog{
+			Version: harVersion,
+			Creator: harCreator{Name: harCreatorName, Version: harCreatorVersion},
+			Pages: []harPage{
+				{
+					StartedDateTime: startedDateTime,
+					ID:              pageID,
+					Title:           pm.URL,
+					PageTimings:     harPageTimings{OnLoad: pm.LoadTime},
+				},
+			},
+			Entries: make([]harEntry, 0, len(pm.Responses)),
+		},
+	}
+
+	ids := make([]int32, 0, len(pm.Responses))
+	for id := range pm.Responses {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		req := pm.Responses[id]
+		doc.Log.Entries = append(doc.Log.Entries, harEntry{
+			Pageref:         pageID,
+			StartedDateTime: req.StartTime.UTC().Format(time.RFC3339Nano),
+			Time:            req.RunningTime,
+			Request: harRequest{
+				Method:      req.Method,
+				URL:         req.URL,
+				HTTPVersion: "HTTP/1.1",
+				Headers:     toHARHeaders(req.RequestHeaders),
+				QueryString: []harHeader{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Response: harResponse{
+				Status:      req.Status,
+				HTTPVersion: "HTTP/1.1",
+				Headers:     toHARHeaders(req.ResponseHeaders),
+				Content: harContent{
+					Size:     req.BodySize,
+					MimeType: req.ContentType,
+				},
+				HeadersSize: -1,
+				BodySize:    req.BodySize,
+			},
+			Timings: harTimings{
+				Blocked: req.Timings.Blocked,
+				DNS:     req.Timings.DNS,
+				Connect: req.Timings.Connect,
+				Send:    req.Timings.Send,
+				Wait:    req.Timings.Wait,
+				Receive: req.Timings.Receive,
+			},
+		})
+	}
+
+	return json.Marshal(doc)
+}
+
+func toHARHeaders(headers map[string]string) []harHeader {
+	result := make([]harHeader, 0, len(headers))
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result = append(result, harHeader{Name: name, Value: headers[name]})
+	}
+
+	return result
+}
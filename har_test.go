@@ -0,0 +1,85 @@
+package pageloadstats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToHAR(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	pm := &PageMeasurements{
+		URL:             "http://example.com",
+		LoadTime:        123,
+		StartedDateTime: started,
+		Responses: map[int32]RequestMeasurements{
+			0: {
+				StartTime:       started,
+				RunningTime:     42,
+				Status:          200,
+				URL:             "http://example.com/style.css",
+				Method:          "GET",
+				ContentType:     "text/css",
+				BodySize:        512,
+				RequestHeaders:  map[string]string{"Accept": "*/*"},
+				ResponseHeaders: map[string]string{"Content-Type": "text/css"},
+				Timings:         RequestTimings{Wait: 40, Receive: 2},
+			},
+		},
+	}
+
+	raw, err := pm.ToHAR()
+	if err != nil {
+		t.Fatalf("ToHAR: %v", err)
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("resulting document is not valid JSON: %v", err)
+	}
+
+	if doc.Log.Version != harVersion {
+		t.Fatalf("want log.version %q, got %q", harVersion, doc.Log.Version)
+	}
+
+	if len(doc.Log.Pages) != 1 {
+		t.Fatalf("want exactly one page, got %d", len(doc.Log.Pages))
+	}
+	if doc.Log.Pages[0].PageTimings.OnLoad != pm.LoadTime {
+		t.Fatalf("want pageTimings.onLoad %d, got %d", pm.LoadTime, doc.Log.Pages[0].PageTimings.OnLoad)
+	}
+
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("want exactly one entry, got %d", len(doc.Log.Entries))
+	}
+
+	entry := doc.Log.Entries[0]
+	if entry.Request.Method != "GET" || entry.Request.URL != "http://example.com/style.css" {
+		t.Fatalf("unexpected request in entry: %+v", entry.Request)
+	}
+	if entry.Response.Status != 200 || entry.Response.Content.MimeType != "text/css" {
+		t.Fatalf("unexpected response in entry: %+v", entry.Response)
+	}
+	if entry.Timings.Wait != 40 || entry.Timings.Receive != 2 {
+		t.Fatalf("unexpected timings in entry: %+v", entry.Timings)
+	}
+}
+
+func TestToHAREmptyResponses(t *testing.T) {
+	pm := &PageMeasurements{URL: "http://example.com"}
+
+	raw, err := pm.ToHAR()
+	if err != nil {
+		t.Fatalf("ToHAR: %v", err)
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("resulting document is not valid JSON: %v", err)
+	}
+
+	if len(doc.Log.Entries) != 0 {
+		t.Fatalf("want no entries for a page with no responses, got %d", len(doc.Log.Entries))
+	}
+}
@@ -1,24 +1,26 @@
 package pageloadstats
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"time"
-
-	phantomjs "github.com/urturn/go-phantomjs" // exported package is phantomjs
 )
 
-var err error
-
 // PageMeasurements is a top level structure to keep measurement data.
 type PageMeasurements struct {
+	URL              string                        `json:"url"`
 	LoadTime         int64                         `json:"loadTime"`
 	Responses        map[int32]RequestMeasurements `json:"responses"`
 	LoadTimeDuration time.Duration
 	ThumbnailFile    string
+	ThumbnailWidth   int
+	ThumbnailHeight  int
+	// Blurhash is a compact placeholder for the thumbnail, set when
+	// ThumbnailOptions.GenerateBlurhash was requested.
+	Blurhash        string
+	StartedDateTime time.Time
 }
 
 // RequestMeasurements is a structure containing data for each child
@@ -28,154 +30,181 @@ type RequestMeasurements struct {
 	EndTime             time.Time `json:"endTime"`
 	RunningTime         int64     `json:"runningTime"`
 	RunningTimeDuration time.Duration
-	Status              int32  `json:"status"`
-	URL                 string `json:"url"`
+	Status              int32             `json:"status"`
+	URL                 string            `json:"url"`
+	Method              string            `json:"method"`
+	RequestHeaders      map[string]string `json:"requestHeaders"`
+	ResponseHeaders     map[string]string `json:"responseHeaders"`
+	ContentType         string            `json:"contentType"`
+	BodySize            int64             `json:"bodySize"`
+	Timings             RequestTimings    `json:"timings"`
+}
+
+// RequestTimings breaks down the running time of a request into the
+// phases HAR 1.2 expects. The PhantomJS backend only ever reports a
+// single wall-clock duration per request, so blocked/dns/connect/send/
+// receive are reported as 0 and the whole duration is attributed to
+// wait; the chromedp backend fills these in from the CDP Network
+// domain.
+type RequestTimings struct {
+	Blocked int64 `json:"blocked"`
+	DNS     int64 `json:"dns"`
+	Connect int64 `json:"connect"`
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
 }
 
 // PageLoadStats defines operations for ivoking and issuing
 // commands to worker processes.
 type PageLoadStats interface {
-	GetMeasurements(string, int, string) (*PageMeasurements, error)
+	// GetMeasurements can be used to get measurements data for page.
+	//
+	// Deprecated: use GetMeasurementsCtx, which accepts a
+	// context.Context instead of a try count and so can express both
+	// deadlines and cancellation.
+	GetMeasurements(rawurl string, nrOfTries int, thumbnailsDir string) (*PageMeasurements, error)
+	// GetMeasurementsCtx can be used to get measurements data for
+	// page. ctx bounds how long the call will wait for a free worker
+	// and for the page load itself; once ctx is done the call returns
+	// ctx.Err() instead of blocking.
+	GetMeasurementsCtx(ctx context.Context, rawurl string, thumbnailsDir string) (*PageMeasurements, error)
+	// GetMeasurementsWithThumbnailOptions behaves like
+	// GetMeasurementsCtx but gives control over how the captured
+	// screenshot is resized, re-encoded, and optionally blurhashed.
+	GetMeasurementsWithThumbnailOptions(ctx context.Context, rawurl string, thumbnailsDir string, thumbnailOptions ThumbnailOptions) (*PageMeasurements, error)
+	// GetMeasurementsBatch measures urls concurrently across the pool
+	// and streams results back on the returned channel as they
+	// complete, never using more than poolSize workers at once.
+	GetMeasurementsBatch(ctx context.Context, urls []string, thumbnailsDir string) (<-chan BatchResult, error)
+	// PoolStats reports the size of the worker pool and how many of
+	// its workers are currently out measuring a page.
+	PoolStats() (size int, inUse int)
 	Close()
 }
 
+// Option configures the PageLoadStats instance returned by New.
+type Option func(*newOptions)
+
+type newOptions struct {
+	backendType BackendType
+}
+
+// WithBackend selects the rendering backend New uses to create
+// workers. The default is BackendPhantomJS for backwards
+// compatibility; new code should opt into BackendChromedp.
+func WithBackend(backendType BackendType) Option {
+	return func(o *newOptions) {
+		o.backendType = backendType
+	}
+}
+
 // New creates a new instance of object implementing
 // LoadTimer interface that can be used to get
 // measurements of load time of web page.
-func New(poolSize int) PageLoadStats {
-	workers := &workersPool{size: poolSize, used: make([]bool, poolSize), workers: make([]*phantomjs.Phantom, poolSize)}
+func New(poolSize int, opts ...Option) PageLoadStats {
+	options := newOptions{backendType: BackendPhantomJS}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-	for i := 0; i < workers.size; i++ {
-		(*workers).workers[i], err = phantomjs.Start()
-		if err != nil {
-			panic(err)
-		}
+	workers, err := newWorkersPool(poolSize, options.backendType)
+	if err != nil {
+		panic(err)
 	}
 
 	return workers
 }
 
-func getJsFunc(url string, thumbnailFile string) string {
-	return fmt.Sprintf(`
-		function(done) {
-			var page = require('webpage').create(),
-				system = require('system'),
-				address = %q,
-				thumbnailFile = %q,
-				loadTime;
-	
-			var diagnosticData = { responses: {} };
-			page.onResourceRequested = function(request) {
-				diagnosticData.responses[request.id] = {startTime: request.time, url: request.url};
-			};
-			page.onResourceReceived = function(response) {
-				var responseData = diagnosticData.responses[response.id];
-				if (responseData) {
-					responseData.status = response.status;
-					responseData.endTime = response.time;
-					responseData.runningTime = responseData.endTime - responseData.startTime;
-					responseData.size = responseData.bodySize;
-				}
-			};
-			
-			page.clearMemoryCache();
-			loadTime = Date.now();
-	
-			page.open(address, function (status) {
-				if (status !== 'success') {
-					system.stderr.writeLine('RES Failed to load the address');
-					done();
-				} else {
-					loadTime = Date.now() - loadTime;
-					diagnosticData.loadTime = loadTime;
-					done(diagnosticData);
-				}
-	
-				if (thumbnailFile !== '') { 
-					page.render(thumbnailFile, { format: 'png' });
-				}
-			});
-		}`, url, thumbnailFile)
-}
-
 // GetMeasurements can be used to get measurements data for page.
-// It will try to find an available phantomjs thread from pool for nrOfTries.
-// If no free thread is found for nrOfTries it will return error.
+// It is GetMeasurementsCtx with a deadline of nrOfTries*200ms, which
+// bounds both the wait for a free worker and the page load itself.
+//
+// Deprecated: use GetMeasurementsCtx instead.
 func (loadTimer *workersPool) GetMeasurements(rawurl string, nrOfTries int, thumbnailsDir string) (*PageMeasurements, error) {
-	_, err := url.ParseRequestURI(rawurl)
-	if err != nil {
-		return nil, err
-	}
-
 	if nrOfTries < 1 {
 		return nil, fmt.Errorf("You have to specify at least one try to get any result")
 	}
 
-	phantom, err := try(nrOfTries, (*loadTimer).getPhantom)
-	if err != nil {
-		return nil, err
-	}
-
-	defer (*loadTimer).releasePhantom(phantom)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(nrOfTries)*200*time.Millisecond)
+	defer cancel()
 
-	performance, err := getMeasurementsInternal(phantom, rawurl, thumbnailsDir)
-	if err != nil {
-		return nil, err
-	}
-
-	return performance, nil
+	return loadTimer.GetMeasurementsCtx(ctx, rawurl, thumbnailsDir)
 }
 
-type getFreePhantomFunc func() (*phantomjs.Phantom, error)
-
-func try(maxTries int, fn getFreePhantomFunc) (*phantomjs.Phantom, error) {
-	attempt := 1
-	for attempt <= maxTries {
-		res, err := fn()
-		if err == nil {
-			return res, nil
-		}
-
-		time.Sleep(200 * time.Millisecond)
-		attempt++
-	}
-
-	return nil, errors.New("Maximum phantom wait time exceeded")
+// GetMeasurementsCtx can be used to get measurements data for page.
+func (loadTimer *workersPool) GetMeasurementsCtx(ctx context.Context, rawurl string, thumbnailsDir string) (*PageMeasurements, error) {
+	return loadTimer.GetMeasurementsWithThumbnailOptions(ctx, rawurl, thumbnailsDir, ThumbnailOptions{})
 }
 
-func getMeasurementsInternal(phantom *phantomjs.Phantom, rawurl string, thumbnailsDir string) (*PageMeasurements, error) {
-	thumbnailFile, err := getThumbnailFile(thumbnailsDir)
+// GetMeasurementsWithThumbnailOptions can be used to get measurements
+// data for page, with control over how the captured screenshot is
+// post-processed.
+func (loadTimer *workersPool) GetMeasurementsWithThumbnailOptions(ctx context.Context, rawurl string, thumbnailsDir string, thumbnailOptions ThumbnailOptions) (*PageMeasurements, error) {
+	_, err := url.ParseRequestURI(rawurl)
 	if err != nil {
 		return nil, err
 	}
 
-	var result interface{}
-	err = phantom.Run(getJsFunc(rawurl, thumbnailFile), &result)
+	b, err := loadTimer.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer loadTimer.release(ctx, b)
 
-	jsonResult, err := json.Marshal(result)
+	return getMeasurementsInternal(ctx, b, rawurl, thumbnailsDir, thumbnailOptions)
+}
+
+func getMeasurementsInternal(ctx context.Context, b backend, rawurl string, thumbnailsDir string, thumbnailOptions ThumbnailOptions) (*PageMeasurements, error) {
+	thumbnailFile, err := getThumbnailFile(thumbnailsDir)
 	if err != nil {
 		return nil, err
 	}
 
-	var performance PageMeasurements
-	err = json.Unmarshal(jsonResult, &performance)
-	if err != nil {
-		return nil, err
+	startedDateTime := time.Now()
+
+	type measureResult struct {
+		performance *PageMeasurements
+		err         error
+	}
+	done := make(chan measureResult, 1)
+	go func() {
+		performance, err := b.measure(rawurl, thumbnailFile)
+		done <- measureResult{performance, err}
+	}()
+
+	var performance *PageMeasurements
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, res.err
+		}
+		performance = res.performance
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
+	performance.URL = rawurl
+	performance.StartedDateTime = startedDateTime
 	performance.LoadTimeDuration = time.Duration(performance.LoadTime) * time.Millisecond
 	if len(thumbnailFile) > 0 {
-		performance.ThumbnailFile = thumbnailFile
+		finalThumbnailFile, blurhash, width, height, err := processThumbnail(thumbnailFile, thumbnailOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		performance.ThumbnailFile = finalThumbnailFile
+		performance.Blurhash = blurhash
+		performance.ThumbnailWidth = width
+		performance.ThumbnailHeight = height
 	}
 
-	for _, v := range performance.Responses {
+	for id, v := range performance.Responses {
 		v.RunningTimeDuration = time.Duration(v.RunningTime) * time.Millisecond
+		performance.Responses[id] = v
 	}
 
-	return &performance, nil
+	return performance, nil
 }
 
 func getThumbnailFile(thumbnailsDir string) (string, error) {
@@ -0,0 +1,117 @@
+package pageloadstats
+
+import (
+	"encoding/json"
+	"fmt"
+
+	phantomjs "github.com/urturn/go-phantomjs" // exported package is phantomjs
+)
+
+// phantomBackend implements backend by driving a single PhantomJS
+// process.
+//
+// Deprecated: use chromedpBackend instead.
+type phantomBackend struct {
+	phantom *phantomjs.Phantom
+}
+
+func newPhantomBackend() (backend, error) {
+	phantom, err := phantomjs.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return &phantomBackend{phantom: phantom}, nil
+}
+
+func (b *phantomBackend) measure(rawurl string, thumbnailFile string) (*PageMeasurements, error) {
+	var result interface{}
+	err := b.phantom.Run(getJsFunc(rawurl, thumbnailFile), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var performance PageMeasurements
+	err = json.Unmarshal(jsonResult, &performance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &performance, nil
+}
+
+func (b *phantomBackend) close() {
+	b.phantom.Exit()
+}
+
+func getJsFunc(url string, thumbnailFile string) string {
+	return fmt.Sprintf(`
+		function(done) {
+			var page = require('webpage').create(),
+				system = require('system'),
+				address = %q,
+				thumbnailFile = %q,
+				loadTime;
+
+			var headersToMap = function(headers) {
+				var result = {}, i;
+				for (i = 0; i < headers.length; i++) {
+					result[headers[i].name] = headers[i].value;
+				}
+				return result;
+			};
+
+			var diagnosticData = { responses: {} };
+			page.onResourceRequested = function(request) {
+				diagnosticData.responses[request.id] = {
+					startTime: request.time,
+					url: request.url,
+					method: request.method,
+					requestHeaders: headersToMap(request.headers)
+				};
+			};
+			page.onResourceReceived = function(response) {
+				var responseData = diagnosticData.responses[response.id];
+				if (responseData && response.stage === 'end') {
+					responseData.status = response.status;
+					responseData.endTime = response.time;
+					responseData.runningTime = responseData.endTime - responseData.startTime;
+					responseData.size = response.bodySize;
+					responseData.bodySize = response.bodySize;
+					responseData.contentType = response.contentType;
+					responseData.responseHeaders = headersToMap(response.headers);
+					responseData.timings = {
+						blocked: 0,
+						dns: 0,
+						connect: 0,
+						send: 0,
+						wait: responseData.runningTime,
+						receive: 0
+					};
+				}
+			};
+
+			page.clearMemoryCache();
+			loadTime = Date.now();
+
+			page.open(address, function (status) {
+				if (status !== 'success') {
+					system.stderr.writeLine('RES Failed to load the address');
+					done();
+				} else {
+					loadTime = Date.now() - loadTime;
+					diagnosticData.loadTime = loadTime;
+					done(diagnosticData);
+				}
+
+				if (thumbnailFile !== '') {
+					page.render(thumbnailFile, { format: 'png' });
+				}
+			});
+		}`, url, thumbnailFile)
+}
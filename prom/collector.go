@@ -0,0 +1,187 @@
+// Package prom exposes pageloadstats measurements as Prometheus
+// metrics, turning the library into a drop-in synthetic monitoring
+// exporter.
+package prom
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mariozski/pageloadstats"
+)
+
+var (
+	loadTimeDesc = prometheus.NewDesc(
+		"pageloadstats_load_time_seconds",
+		"Time taken for the page to finish loading.",
+		[]string{"url"}, nil,
+	)
+	responseRunningTimeDesc = prometheus.NewDesc(
+		"pageloadstats_response_running_time_seconds",
+		"Running time of responses observed while loading a page.",
+		[]string{"url", "status"}, nil,
+	)
+	responseStatusTotalDesc = prometheus.NewDesc(
+		"pageloadstats_response_status_total",
+		"Count of responses observed for a page, by status code.",
+		[]string{"url", "status"}, nil,
+	)
+	poolWorkersInUseDesc = prometheus.NewDesc(
+		"pageloadstats_pool_workers_in_use",
+		"Number of workers from the pool currently measuring a page.",
+		nil, nil,
+	)
+
+	responseRunningTimeBuckets = prometheus.DefBuckets
+)
+
+// poolStatter is implemented by PageLoadStats instances that can
+// report how busy their worker pool is. It is satisfied by every
+// pageloadstats.PageLoadStats returned by pageloadstats.New.
+type poolStatter interface {
+	PoolStats() (size int, inUse int)
+}
+
+// collector implements prometheus.Collector by periodically scraping
+// a fixed set of URLs through a pageloadstats.PageLoadStats and
+// caching the latest measurement for each.
+type collector struct {
+	pls      pageloadstats.PageLoadStats
+	urls     []string
+	interval time.Duration
+
+	mu     sync.Mutex
+	latest map[string]*pageloadstats.PageMeasurements
+}
+
+// NewCollector returns a prometheus.Collector that scrapes urls every
+// interval using pls, exposing the results as load time, per-response
+// timing, response status counts, and pool utilization metrics.
+//
+// A positive interval starts a background goroutine that scrapes on
+// that cadence for the lifetime of the process. An interval of zero
+// or less disables the background goroutine; Collect scrapes
+// synchronously instead, so every call to Collect pays for a full
+// round of page loads.
+func NewCollector(pls pageloadstats.PageLoadStats, urls []string, interval time.Duration) prometheus.Collector {
+	c := &collector{
+		pls:      pls,
+		urls:     urls,
+		interval: interval,
+		latest:   make(map[string]*pageloadstats.PageMeasurements, len(urls)),
+	}
+
+	if interval > 0 {
+		go c.run()
+	}
+
+	return c
+}
+
+// run scrapes all configured URLs every interval for the lifetime of
+// the process.
+func (c *collector) run() {
+	ctx := context.Background()
+	c.scrape(ctx)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.scrape(ctx)
+	}
+}
+
+func (c *collector) scrape(ctx context.Context) {
+	results, err := c.pls.GetMeasurementsBatch(ctx, c.urls, "")
+	if err != nil {
+		return
+	}
+
+	for result := range results {
+		if result.Err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		c.latest[result.URL] = result.Measurements
+		c.mu.Unlock()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- loadTimeDesc
+	ch <- responseRunningTimeDesc
+	ch <- responseStatusTotalDesc
+	ch <- poolWorkersInUseDesc
+}
+
+// runningTimeHistogram accumulates response running times into
+// cumulative bucket counts, so they can be reported as a single
+// Prometheus histogram per (url, status) instead of one gauge sample
+// per response — a page with several responses sharing a status code
+// (the common case) would otherwise emit duplicate metric identities
+// in the same Collect call.
+type runningTimeHistogram struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+func (h *runningTimeHistogram) observe(seconds float64) {
+	h.count++
+	h.sum += seconds
+
+	if h.buckets == nil {
+		h.buckets = make(map[float64]uint64, len(responseRunningTimeBuckets))
+	}
+	for _, bound := range responseRunningTimeBuckets {
+		if seconds <= bound {
+			h.buckets[bound]++
+		}
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	if c.interval <= 0 {
+		c.scrape(context.Background())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for url, measurements := range c.latest {
+		ch <- prometheus.MustNewConstMetric(loadTimeDesc, prometheus.GaugeValue, measurements.LoadTimeDuration.Seconds(), url)
+
+		statusCounts := make(map[int32]int)
+		runningTimes := make(map[int32]*runningTimeHistogram)
+		for _, response := range measurements.Responses {
+			statusCounts[response.Status]++
+
+			hist, ok := runningTimes[response.Status]
+			if !ok {
+				hist = &runningTimeHistogram{}
+				runningTimes[response.Status] = hist
+			}
+			hist.observe(response.RunningTimeDuration.Seconds())
+		}
+
+		for status, hist := range runningTimes {
+			ch <- prometheus.MustNewConstHistogram(responseRunningTimeDesc, hist.count, hist.sum, hist.buckets, url, strconv.Itoa(int(status)))
+		}
+		for status, count := range statusCounts {
+			ch <- prometheus.MustNewConstMetric(responseStatusTotalDesc, prometheus.CounterValue, float64(count), url, strconv.Itoa(int(status)))
+		}
+	}
+
+	if statter, ok := c.pls.(poolStatter); ok {
+		_, inUse := statter.PoolStats()
+		ch <- prometheus.MustNewConstMetric(poolWorkersInUseDesc, prometheus.GaugeValue, float64(inUse))
+	}
+}
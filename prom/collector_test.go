@@ -0,0 +1,221 @@
+package prom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/mariozski/pageloadstats"
+)
+
+// fakePageLoadStats is a pageloadstats.PageLoadStats that serves
+// canned measurements instead of driving a real backend, so Collect
+// can be tested without a browser or phantomjs process.
+type fakePageLoadStats struct {
+	measurements map[string]*pageloadstats.PageMeasurements
+	size, inUse  int
+}
+
+func (f *fakePageLoadStats) GetMeasurements(rawurl string, nrOfTries int, thumbnailsDir string) (*pageloadstats.PageMeasurements, error) {
+	return f.measurements[rawurl], nil
+}
+
+func (f *fakePageLoadStats) GetMeasurementsCtx(ctx context.Context, rawurl string, thumbnailsDir string) (*pageloadstats.PageMeasurements, error) {
+	return f.measurements[rawurl], nil
+}
+
+func (f *fakePageLoadStats) GetMeasurementsWithThumbnailOptions(ctx context.Context, rawurl string, thumbnailsDir string, thumbnailOptions pageloadstats.ThumbnailOptions) (*pageloadstats.PageMeasurements, error) {
+	return f.measurements[rawurl], nil
+}
+
+func (f *fakePageLoadStats) GetMeasurementsBatch(ctx context.Context, urls []string, thumbnailsDir string) (<-chan pageloadstats.BatchResult, error) {
+	out := make(chan pageloadstats.BatchResult, len(urls))
+	for _, u := range urls {
+		out <- pageloadstats.BatchResult{URL: u, Measurements: f.measurements[u]}
+	}
+	close(out)
+	return out, nil
+}
+
+func (f *fakePageLoadStats) PoolStats() (size int, inUse int) {
+	return f.size, f.inUse
+}
+
+func (f *fakePageLoadStats) Close() {}
+
+func TestCollectorDescribe(t *testing.T) {
+	c := &collector{}
+
+	ch := make(chan *prometheus.Desc, 10)
+	c.Describe(ch)
+	close(ch)
+
+	var descs []*prometheus.Desc
+	for d := range ch {
+		descs = append(descs, d)
+	}
+
+	want := []*prometheus.Desc{loadTimeDesc, responseRunningTimeDesc, responseStatusTotalDesc, poolWorkersInUseDesc}
+	if len(descs) != len(want) {
+		t.Fatalf("want %d descriptors, got %d", len(want), len(descs))
+	}
+	for i, d := range want {
+		if descs[i] != d {
+			t.Fatalf("descriptor %d: want %v, got %v", i, d, descs[i])
+		}
+	}
+}
+
+func TestCollectorCollectSynchronousScrape(t *testing.T) {
+	fake := &fakePageLoadStats{
+		measurements: map[string]*pageloadstats.PageMeasurements{
+			"http://example.com": {LoadTimeDuration: 200 * time.Millisecond},
+		},
+		size:  2,
+		inUse: 1,
+	}
+
+	c := NewCollector(fake, []string{"http://example.com"}, 0)
+
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+
+	var sawLoadTime, sawPoolWorkers bool
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("write metric: %v", err)
+		}
+		if pb.Gauge == nil {
+			continue
+		}
+		switch len(pb.Label) {
+		case 1:
+			sawLoadTime = true
+			if got := pb.Label[0].GetValue(); got != "http://example.com" {
+				t.Fatalf("want load time labelled with url, got %q", got)
+			}
+			if got := pb.Gauge.GetValue(); got != 0.2 {
+				t.Fatalf("want load time 0.2s, got %v", got)
+			}
+		case 0:
+			sawPoolWorkers = true
+			if got := pb.Gauge.GetValue(); got != 1 {
+				t.Fatalf("want 1 worker in use, got %v", got)
+			}
+		}
+	}
+
+	if !sawLoadTime {
+		t.Fatal("expected a load time metric for the scraped url")
+	}
+	if !sawPoolWorkers {
+		t.Fatal("expected a pool_workers_in_use metric")
+	}
+}
+
+func TestCollectorCollectRunningTimeHistogram(t *testing.T) {
+	c := &collector{
+		interval: time.Minute, // avoid Collect's synchronous scrape path; pls is nil here
+		latest: map[string]*pageloadstats.PageMeasurements{
+			"http://example.com": {
+				Responses: map[int32]pageloadstats.RequestMeasurements{
+					0: {Status: 200, RunningTimeDuration: 50 * time.Millisecond},
+					1: {Status: 200, RunningTimeDuration: 900 * time.Millisecond},
+					2: {Status: 404, RunningTimeDuration: 10 * time.Millisecond},
+				},
+			},
+		},
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+
+	var hist200 *dto.Metric
+	var statusCounts = map[string]float64{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("write metric: %v", err)
+		}
+		if pb.Histogram != nil {
+			for _, l := range pb.GetLabel() {
+				if l.GetName() == "status" && l.GetValue() == "200" {
+					hist200 = &pb
+				}
+			}
+		}
+		if pb.Counter != nil {
+			var status string
+			for _, l := range pb.GetLabel() {
+				if l.GetName() == "status" {
+					status = l.GetValue()
+				}
+			}
+			statusCounts[status] = pb.Counter.GetValue()
+		}
+	}
+
+	if statusCounts["200"] != 2 || statusCounts["404"] != 1 {
+		t.Fatalf("want status counts {200: 2, 404: 1}, got %v", statusCounts)
+	}
+
+	if hist200 == nil {
+		t.Fatal("expected a running time histogram for status 200")
+	}
+	if got := hist200.Histogram.GetSampleCount(); got != 2 {
+		t.Fatalf("want sample count 2 for status 200, got %d", got)
+	}
+	wantSum := 0.05 + 0.9
+	if got := hist200.Histogram.GetSampleSum(); got < wantSum-1e-9 || got > wantSum+1e-9 {
+		t.Fatalf("want sample sum %v, got %v", wantSum, got)
+	}
+
+	buckets := make(map[float64]uint64, len(hist200.Histogram.Bucket))
+	for _, b := range hist200.Histogram.Bucket {
+		buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+	}
+	// 0.05s falls in every bucket from 0.05 up; 0.9s only joins at 1 and above.
+	if got := buckets[0.05]; got != 1 {
+		t.Fatalf("want cumulative count 1 at bucket 0.05, got %d", got)
+	}
+	if got := buckets[0.5]; got != 1 {
+		t.Fatalf("want cumulative count 1 at bucket 0.5, got %d", got)
+	}
+	if got := buckets[1]; got != 2 {
+		t.Fatalf("want cumulative count 2 at bucket 1, got %d", got)
+	}
+}
+
+func TestRunningTimeHistogramObserve(t *testing.T) {
+	var h runningTimeHistogram
+	h.observe(0.01)
+	h.observe(0.2)
+	h.observe(3)
+
+	if h.count != 3 {
+		t.Fatalf("want count 3, got %d", h.count)
+	}
+	wantSum := 0.01 + 0.2 + 3.0
+	if h.sum < wantSum-1e-9 || h.sum > wantSum+1e-9 {
+		t.Fatalf("want sum %v, got %v", wantSum, h.sum)
+	}
+
+	// Cumulative: a bucket counts every observation at or below its
+	// upper bound, so each wider bucket includes everything the
+	// narrower ones did.
+	if h.buckets[0.025] != 1 {
+		t.Fatalf("want bucket 0.025 to hold just the 0.01 observation, got %d", h.buckets[0.025])
+	}
+	if h.buckets[0.25] != 2 {
+		t.Fatalf("want bucket 0.25 to hold the 0.01 and 0.2 observations, got %d", h.buckets[0.25])
+	}
+	if h.buckets[5] != 3 {
+		t.Fatalf("want bucket 5 to hold all three observations, got %d", h.buckets[5])
+	}
+}
@@ -0,0 +1,135 @@
+package pageloadstats
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+
+	"github.com/buckket/go-blurhash"
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailOptions controls how a page screenshot is post-processed
+// after the backend captures it. The zero value leaves the raw
+// screenshot untouched, which is what GetMeasurements,
+// GetMeasurementsCtx, and GetMeasurementsBatch use.
+type ThumbnailOptions struct {
+	// Width and Height resize the screenshot when both are non-zero.
+	// Leaving either at 0 keeps the backend's native screenshot size.
+	Width  int
+	Height int
+	// Format re-encodes the screenshot as "png", "jpeg", or "webp".
+	// Empty leaves it in whatever format the backend captured (PNG).
+	Format string
+	// Quality is the jpeg/webp encode quality (1-100). Ignored for
+	// png, and defaults to 90 when Format requires it but Quality is 0.
+	Quality int
+	// GenerateBlurhash computes a compact blurhash placeholder for the
+	// screenshot, stored on PageMeasurements.Blurhash.
+	GenerateBlurhash bool
+	// BlurhashXComponents and BlurhashYComponents control blurhash
+	// detail; both default to 4 when GenerateBlurhash is set and left
+	// at 0.
+	BlurhashXComponents int
+	BlurhashYComponents int
+}
+
+// processThumbnail resizes and/or re-encodes the screenshot at path
+// according to opts and, if requested, computes its blurhash. It
+// returns the final thumbnail path (path unless opts.Format is set, in
+// which case "."+opts.Format is appended, even for "png") along with
+// the blurhash and the
+// thumbnail's final dimensions.
+func processThumbnail(path string, opts ThumbnailOptions) (finalPath string, blurhashStr string, width int, height int, err error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	resized := false
+
+	if opts.Width > 0 && opts.Height > 0 && (opts.Width != width || opts.Height != height) {
+		dst := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+		img = dst
+		width, height = opts.Width, opts.Height
+		resized = true
+	}
+
+	if opts.GenerateBlurhash {
+		xComponents, yComponents := opts.BlurhashXComponents, opts.BlurhashYComponents
+		if xComponents == 0 {
+			xComponents = 4
+		}
+		if yComponents == 0 {
+			yComponents = 4
+		}
+
+		blurhashStr, err = blurhash.Encode(xComponents, yComponents, img)
+		if err != nil {
+			return "", "", 0, 0, err
+		}
+	}
+
+	if opts.Format == "" && !resized {
+		return path, blurhashStr, width, height, nil
+	}
+
+	encoded, outPath, err := encodeThumbnail(path, img, opts)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+
+	if err := ioutil.WriteFile(outPath, encoded, 0644); err != nil {
+		return "", "", 0, 0, err
+	}
+
+	return outPath, blurhashStr, width, height, nil
+}
+
+func encodeThumbnail(path string, img image.Image, opts ThumbnailOptions) ([]byte, string, error) {
+	format := opts.Format
+	if format == "" {
+		format = "png"
+	}
+
+	quality := opts.Quality
+	if quality == 0 {
+		quality = 90
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+	case "jpeg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+	case "webp":
+		if err := webpEncode(&buf, img, quality); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", fmt.Errorf("pageloadstats: unsupported thumbnail format %q", opts.Format)
+	}
+
+	outPath := path
+	if opts.Format != "" {
+		outPath = path + "." + format
+	}
+
+	return buf.Bytes(), outPath, nil
+}
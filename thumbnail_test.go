@@ -0,0 +1,121 @@
+package pageloadstats
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestPNG(t *testing.T, width, height int) string {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "thumb.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create test png: %v", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("encode test png: %v", err)
+	}
+
+	return path
+}
+
+func TestProcessThumbnailNoOpLeavesFileInPlace(t *testing.T) {
+	path := writeTestPNG(t, 10, 8)
+
+	outPath, blurhash, width, height, err := processThumbnail(path, ThumbnailOptions{})
+	if err != nil {
+		t.Fatalf("processThumbnail: %v", err)
+	}
+
+	if outPath != path {
+		t.Fatalf("want the zero-value options to leave the thumbnail at %q, got %q", path, outPath)
+	}
+	if blurhash != "" {
+		t.Fatalf("want no blurhash when GenerateBlurhash is false, got %q", blurhash)
+	}
+	if width != 10 || height != 8 {
+		t.Fatalf("want dimensions 10x8, got %dx%d", width, height)
+	}
+}
+
+func TestProcessThumbnailResize(t *testing.T) {
+	path := writeTestPNG(t, 20, 20)
+
+	outPath, _, width, height, err := processThumbnail(path, ThumbnailOptions{Width: 5, Height: 5})
+	if err != nil {
+		t.Fatalf("processThumbnail: %v", err)
+	}
+
+	if outPath != path {
+		t.Fatalf("want resize without a format change to keep the original path %q, got %q", path, outPath)
+	}
+	if width != 5 || height != 5 {
+		t.Fatalf("want resized dimensions 5x5, got %dx%d", width, height)
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		t.Fatalf("open resized thumbnail: %v", err)
+	}
+	defer f.Close()
+
+	decoded, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("decode resized thumbnail: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 5 || b.Dy() != 5 {
+		t.Fatalf("want the thumbnail on disk to actually be 5x5, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcessThumbnailFormatConversion(t *testing.T) {
+	path := writeTestPNG(t, 4, 4)
+
+	outPath, _, _, _, err := processThumbnail(path, ThumbnailOptions{Format: "jpeg"})
+	if err != nil {
+		t.Fatalf("processThumbnail: %v", err)
+	}
+
+	wantPath := path + ".jpeg"
+	if outPath != wantPath {
+		t.Fatalf("want converted thumbnail at %q, got %q", wantPath, outPath)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("converted thumbnail not written to disk: %v", err)
+	}
+}
+
+func TestProcessThumbnailBlurhash(t *testing.T) {
+	path := writeTestPNG(t, 16, 16)
+
+	_, blurhash, _, _, err := processThumbnail(path, ThumbnailOptions{GenerateBlurhash: true})
+	if err != nil {
+		t.Fatalf("processThumbnail: %v", err)
+	}
+
+	if blurhash == "" {
+		t.Fatal("want a non-empty blurhash when GenerateBlurhash is true")
+	}
+}
+
+func TestProcessThumbnailUnsupportedFormat(t *testing.T) {
+	path := writeTestPNG(t, 4, 4)
+
+	if _, _, _, _, err := processThumbnail(path, ThumbnailOptions{Format: "bmp"}); err == nil {
+		t.Fatal("want an error for an unsupported thumbnail format")
+	}
+}
@@ -0,0 +1,12 @@
+package pageloadstats
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+func webpEncode(w io.Writer, img image.Image, quality int) error {
+	return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+}
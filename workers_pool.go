@@ -1,55 +1,97 @@
 package pageloadstats
 
 import (
-	"fmt"
-	"sync"
-
-	phantomjs "github.com/urturn/go-phantomjs"
+	"context"
+	"sync/atomic"
 )
 
+// workersPool hands backends out over a buffered channel of idle
+// workers, so acquisition is a select alongside ctx.Done() instead of
+// the mutex + linear scan + fixed polling interval this used to be.
 type workersPool struct {
-	mu      sync.Mutex
-	used    []bool
-	workers []*phantomjs.Phantom
-	size    int
+	idle chan backend
+	size int
+	// lost counts workers that were killed after a cancelled
+	// measurement and then failed to restart; they're gone for good and no longer
+	// count towards size in PoolStats.
+	lost int64
+	// newBackend creates a replacement worker; a field rather than a
+	// direct call to the package-level newBackend so tests can supply
+	// a fake without driving a real browser.
+	newBackend func() (backend, error)
+}
+
+func newWorkersPool(size int, backendType BackendType) (*workersPool, error) {
+	p := &workersPool{
+		idle: make(chan backend, size),
+		size: size,
+		newBackend: func() (backend, error) {
+			return newBackend(backendType)
+		},
+	}
+
+	for i := 0; i < size; i++ {
+		b, err := p.newBackend()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle <- b
+	}
+
+	return p, nil
 }
 
 // Close method should be used at the end to get rid of
 // resources that LoadTimer has used.
 func (p *workersPool) Close() {
-	for i := 0; i < (*p).size; i++ {
-		if (*p).workers[i] == nil {
-			continue
+	for i := 0; i < p.size; i++ {
+		select {
+		case b := <-p.idle:
+			b.close()
+		default:
+			return
 		}
-
-		(*p).workers[i].Exit()
 	}
 }
 
-func (p *workersPool) getPhantom() (*phantomjs.Phantom, error) {
-	(*p).mu.Lock()
-	defer (*p).mu.Unlock()
+// PoolStats reports the size of the worker pool, excluding any
+// workers permanently lost after a failed restart, and how many of
+// the remaining workers are currently out measuring a page.
+func (p *workersPool) PoolStats() (size int, inUse int) {
+	size = p.size - int(atomic.LoadInt64(&p.lost))
+	return size, size - len(p.idle)
+}
 
-	for i := 0; i < (*p).size; i++ {
-		if !(*p).used[i] {
-			(*p).used[i] = true
-			return (*p).workers[i], nil
-		}
+// acquire returns the next idle backend, or ctx.Err() if ctx is done
+// before one becomes available.
+func (p *workersPool) acquire(ctx context.Context) (backend, error) {
+	select {
+	case b := <-p.idle:
+		return b, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-
-	return nil, fmt.Errorf("Could not get phantom process")
 }
 
-func (p *workersPool) releasePhantom(phantom *phantomjs.Phantom) error {
-	(*p).mu.Lock()
-	defer (*p).mu.Unlock()
+// release returns b to the pool. If ctx was cancelled mid-measurement
+// b may be wedged on a hung page load, so it is killed and replaced
+// instead of being trusted back into rotation.
+func (p *workersPool) release(ctx context.Context, b backend) {
+	if ctx.Err() != nil {
+		b.close()
 
-	for i := 0; i < (*p).size; i++ {
-		if (*p).workers[i] == phantom {
-			(*p).used[i] = false
-			return nil
+		replacement, err := p.newBackend()
+		if err != nil {
+			// The pool permanently shrinks by one rather than blocking
+			// a future acquire on a backend we know can't start; lost
+			// tracks this so PoolStats doesn't keep reporting the slot
+			// as merely "in use".
+			atomic.AddInt64(&p.lost, 1)
+			return
 		}
+		b = replacement
 	}
 
-	return fmt.Errorf("Could not release phantom process")
+	p.idle <- b
 }
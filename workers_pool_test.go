@@ -0,0 +1,104 @@
+package pageloadstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	p := newTestPool(1)
+
+	b, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	if _, inUse := p.PoolStats(); inUse != 1 {
+		t.Fatalf("want 1 worker in use after acquire, got %d", inUse)
+	}
+
+	p.release(context.Background(), b)
+
+	if _, inUse := p.PoolStats(); inUse != 0 {
+		t.Fatalf("want 0 workers in use after release, got %d", inUse)
+	}
+}
+
+func TestAcquireCancelledContextDoesNotBlock(t *testing.T) {
+	p := newTestPool(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		if _, err := p.acquire(ctx); err == nil {
+			t.Error("expected acquire on an empty pool with a cancelled context to return an error")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("acquire blocked past the context's cancellation")
+	}
+}
+
+func TestReleaseAfterCancelRestartsBackend(t *testing.T) {
+	p := newTestPool(1)
+
+	b, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p.release(ctx, b)
+
+	if !b.(*fakeBackend).isClosed() {
+		t.Fatal("expected the backend held across a cancelled context to be closed")
+	}
+
+	if size, inUse := p.PoolStats(); size != 1 || inUse != 0 {
+		t.Fatalf("want size=1 inUse=0 after a successful restart, got size=%d inUse=%d", size, inUse)
+	}
+
+	replacement, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after restart: %v", err)
+	}
+	if replacement == b {
+		t.Fatal("expected a fresh backend after a cancelled release, not the same instance")
+	}
+}
+
+func TestReleaseAfterCancelTracksLostCapacity(t *testing.T) {
+	p := newTestPool(1)
+
+	b, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	p.newBackend = func() (backend, error) {
+		return nil, errors.New("boom: backend can't be restarted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p.release(ctx, b)
+
+	size, inUse := p.PoolStats()
+	if size != 0 {
+		t.Fatalf("want pool size to shrink to 0 after a failed restart, got %d", size)
+	}
+	if inUse != 0 {
+		t.Fatalf("a permanently lost worker must not be reported as in use, got %d", inUse)
+	}
+}